@@ -0,0 +1,187 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RequestLog carries the data of an outgoing Http request for logging purposes.
+type RequestLog struct {
+	Method  string
+	Url     string
+	Headers http.Header
+	Body    []byte
+}
+
+// ResponseLog carries the data of an incoming Http response for logging purposes.
+type ResponseLog struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+}
+
+// Logger lets a Service report outgoing requests and incoming responses
+// without dumping them straight to stdout. Implement it to wire the library
+// into an application's own logging stack.
+type Logger interface {
+	LogRequest(RequestLog)
+	LogResponse(ResponseLog)
+	// LogCurl receives a shell-escaped curl command reproducing an outgoing
+	// request, emitted when RequestConfig.DumpAsCurl (or the Service default)
+	// is true.
+	LogCurl(command string)
+	// LogRetry is called before sleeping ahead of retry attempt (1-based) for
+	// method/url, with the duration the request will wait before retrying.
+	LogRetry(attempt uint, method string, url string, wait time.Duration)
+	// LogDebug receives miscellaneous diagnostic messages, e.g. unexpected nil
+	// client/request/response states, that don't fit RequestLog/ResponseLog.
+	LogDebug(message string)
+}
+
+const defaultRequestLogTemplate = `---- HTTP request ----
+{{.Method}} {{.Url}}
+{{range $key, $values := .Headers}}{{$key}}: {{join $values ", "}}
+{{end}}{{if .Body}}
+{{printf "%s" .Body}}
+{{end}}-----------------------
+`
+
+const defaultResponseLogTemplate = `---- HTTP response ----
+{{.Method}} {{.Url}} -> {{.StatusCode}}
+{{range $key, $values := .Headers}}{{$key}}: {{join $values ", "}}
+{{end}}{{if .Body}}
+{{printf "%s" .Body}}
+{{end}}------------------------
+`
+
+// DefaultLogger is the Logger used when a Service is not given one explicitly.
+// It renders RequestLog/ResponseLog values through text/template and writes
+// the result to Writer (os.Stdout by default).
+type DefaultLogger struct {
+	Writer           io.Writer
+	requestTemplate  *template.Template
+	responseTemplate *template.Template
+}
+
+// NewDefaultLogger returns a DefaultLogger writing to os.Stdout.
+func NewDefaultLogger() *DefaultLogger {
+	funcMap := template.FuncMap{
+		"join": func(values []string, sep string) string {
+			return strings.Join(values, sep)
+		},
+	}
+
+	return &DefaultLogger{
+		Writer:           os.Stdout,
+		requestTemplate:  template.Must(template.New("request").Funcs(funcMap).Parse(defaultRequestLogTemplate)),
+		responseTemplate: template.Must(template.New("response").Funcs(funcMap).Parse(defaultResponseLogTemplate)),
+	}
+}
+
+func (logger *DefaultLogger) LogRequest(requestLog RequestLog) {
+	writer := logger.writer()
+	if err := logger.requestTemplate.Execute(writer, requestLog); err != nil {
+		fmt.Fprintf(writer, "DEBUG - failed to render request log: %s\n", err.Error())
+	}
+}
+
+func (logger *DefaultLogger) LogResponse(responseLog ResponseLog) {
+	writer := logger.writer()
+	if err := logger.responseTemplate.Execute(writer, responseLog); err != nil {
+		fmt.Fprintf(writer, "DEBUG - failed to render response log: %s\n", err.Error())
+	}
+}
+
+func (logger *DefaultLogger) LogCurl(command string) {
+	fmt.Fprintf(logger.writer(), "%s\n", command)
+}
+
+func (logger *DefaultLogger) LogRetry(attempt uint, method string, url string, wait time.Duration) {
+	fmt.Fprintf(logger.writer(), "starting retry %v for %s %s after %s\n", attempt, method, url, wait)
+}
+
+func (logger *DefaultLogger) LogDebug(message string) {
+	fmt.Fprintf(logger.writer(), "DEBUG - %s\n", message)
+}
+
+func (logger *DefaultLogger) writer() io.Writer {
+	if logger.Writer != nil {
+		return logger.Writer
+	}
+	return os.Stdout
+}
+
+// redactHeaders returns a copy of headers with the values of any header whose
+// key matches (case-insensitively) one of redact replaced by "***".
+func redactHeaders(headers http.Header, redact []string) http.Header {
+	if headers == nil || len(redact) == 0 {
+		return headers
+	}
+
+	redacted := headers.Clone()
+	for _, key := range redact {
+		if redacted.Get(key) != "" {
+			redacted.Set(key, "***")
+		}
+	}
+
+	return redacted
+}
+
+// redactBody returns a copy of a JSON body with the values of any top-level
+// or nested object field whose name matches (case-insensitively) one of
+// redact replaced by "***". Non-JSON bodies, or an empty redact list, are
+// returned unchanged.
+func redactBody(body []byte, redact []string) []byte {
+	if len(body) == 0 || len(redact) == 0 {
+		return body
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+
+	redactJSONValue(data, redact)
+
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+
+	return redacted
+}
+
+func redactJSONValue(value interface{}, redact []string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, fieldValue := range v {
+			if matchesAny(key, redact) {
+				v[key] = "***"
+				continue
+			}
+			redactJSONValue(fieldValue, redact)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactJSONValue(item, redact)
+		}
+	}
+}
+
+func matchesAny(key string, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	errortools "github.com/leapforce-libraries/go_errortools"
+	"net/http"
+)
+
+// RoundTrip executes a single (possibly retried) RetryableRequest and returns
+// its response.
+type RoundTrip func(request *RetryableRequest) (*http.Response, *errortools.Error)
+
+// Middleware wraps a RoundTrip with cross-cutting behaviour (auth, signing,
+// rate limiting, circuit breaking, tracing, caching, ...) and returns the
+// wrapped RoundTrip.
+type Middleware func(next RoundTrip) RoundTrip
+
+// Use appends mw to the Service's middleware chain. Middlewares run in the
+// order they were added, each wrapping the next, with doWithRetry at the
+// innermost position.
+func (service *Service) Use(mw ...Middleware) {
+	service.middlewares = append(service.middlewares, mw...)
+}
+
+// chain builds the RoundTrip that HttpRequest invokes: every configured
+// middleware wrapped around base, outermost first.
+func (service *Service) chain(base RoundTrip) RoundTrip {
+	roundTrip := base
+	for i := len(service.middlewares) - 1; i >= 0; i-- {
+		roundTrip = service.middlewares[i](roundTrip)
+	}
+	return roundTrip
+}
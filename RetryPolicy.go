@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides, after a (possibly failed) attempt, whether the request
+// should be retried and how long to wait before doing so. attempt is the
+// number of retries already made (0 for the first retry decision, taken
+// after the initial attempt).
+type RetryPolicy interface {
+	ShouldRetry(request *http.Request, response *http.Response, err error, attempt uint) (bool, time.Duration)
+}
+
+type retryNonIdempotentContextKey struct{}
+
+// withRetryNonIdempotent marks the request's context so that a RetryPolicy can
+// tell this request opted in to retrying non-idempotent methods.
+func withRetryNonIdempotent(ctx context.Context, retryNonIdempotent bool) context.Context {
+	if !retryNonIdempotent {
+		return ctx
+	}
+	return context.WithValue(ctx, retryNonIdempotentContextKey{}, true)
+}
+
+func isRetryNonIdempotent(ctx context.Context) bool {
+	retryNonIdempotent, _ := ctx.Value(retryNonIdempotentContextKey{}).(bool)
+	return retryNonIdempotent
+}
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// DefaultRetryPolicy is the RetryPolicy used when no other policy is configured.
+// It honors Retry-After headers on 429/503 responses, skips retrying
+// non-idempotent methods (POST, PATCH) unless the request opted in via
+// RequestConfig.RetryNonIdempotent, applies full-jitter backoff, and
+// classifies common transient network errors as retryable.
+type DefaultRetryPolicy struct {
+	// BaseDelay is the backoff base used to compute the full-jitter delay cap
+	// (BaseDelay*2^attempt). Defaults to 1 second when zero.
+	BaseDelay time.Duration
+	// MaxDelay caps the full-jitter backoff delay. Defaults to 30 seconds when zero.
+	MaxDelay time.Duration
+}
+
+// NewDefaultRetryPolicy returns a DefaultRetryPolicy with the library defaults.
+func NewDefaultRetryPolicy() *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		BaseDelay: time.Second,
+		MaxDelay:  30 * time.Second,
+	}
+}
+
+func (policy *DefaultRetryPolicy) ShouldRetry(request *http.Request, response *http.Response, err error, attempt uint) (bool, time.Duration) {
+	if request != nil && !idempotentMethods[request.Method] {
+		if request.Context() == nil || !isRetryNonIdempotent(request.Context()) {
+			return false, 0
+		}
+	}
+
+	retryable := false
+
+	if response != nil {
+		switch response.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			retryable = true
+		default:
+			retryable = response.StatusCode/100 == 5
+		}
+
+		if retryable {
+			if wait, ok := retryAfter(response); ok {
+				return true, wait
+			}
+		}
+	}
+
+	if err != nil && isRetryableNetworkError(err) {
+		retryable = true
+	}
+
+	if !retryable {
+		return false, 0
+	}
+
+	return true, policy.fullJitterBackoff(attempt)
+}
+
+// fullJitterBackoff implements the "full jitter" strategy:
+// sleep = rand(0, min(cap, base*2^attempt))
+func (policy *DefaultRetryPolicy) fullJitterBackoff(attempt uint) time.Duration {
+	base := policy.BaseDelay
+	if base <= 0 {
+		base = time.Second
+	}
+	cap_ := policy.MaxDelay
+	if cap_ <= 0 {
+		cap_ = 30 * time.Second
+	}
+
+	max := time.Duration(float64(base) * math.Pow(2, float64(attempt)))
+	if max > cap_ || max <= 0 {
+		max = cap_
+	}
+
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// retryAfter parses a Retry-After header in either its seconds or HTTP-date form.
+func retryAfter(response *http.Response) (time.Duration, bool) {
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// isRetryableNetworkError classifies low-level network errors as retryable
+// when their message (case-insensitively) mentions a connection reset, a
+// broken pipe, EOF, a timeout (including TLS handshake timeouts), a DNS
+// lookup failure ("no such host" or a temporary name resolution failure) or
+// a refused connection.
+func isRetryableNetworkError(err error) bool {
+	message := strings.ToLower(err.Error())
+
+	for _, substr := range []string{
+		"connection reset",
+		"broken pipe",
+		"eof",
+		"tls handshake timeout",
+		"no such host",
+		"timeout",
+		"temporary failure in name resolution",
+		"connection refused",
+	} {
+		if strings.Contains(message, substr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFullJitterBackoff(t *testing.T) {
+	policy := NewDefaultRetryPolicy()
+
+	for attempt := uint(0); attempt < 8; attempt++ {
+		wait := policy.fullJitterBackoff(attempt)
+		if wait < 0 {
+			t.Fatalf("attempt %d: wait is negative: %s", attempt, wait)
+		}
+		if wait > policy.MaxDelay {
+			t.Fatalf("attempt %d: wait %s exceeds MaxDelay %s", attempt, wait, policy.MaxDelay)
+		}
+	}
+}
+
+func TestFullJitterBackoffZeroValuePolicy(t *testing.T) {
+	policy := &DefaultRetryPolicy{}
+
+	wait := policy.fullJitterBackoff(0)
+	if wait < 0 || wait > 30*time.Second {
+		t.Fatalf("expected wait within default 30s cap, got %s", wait)
+	}
+}
+
+func newResponseWithHeader(key string, value string) *http.Response {
+	response := &http.Response{Header: make(http.Header)}
+	if value != "" {
+		response.Header.Set(key, value)
+	}
+	return response
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	response := newResponseWithHeader("Retry-After", "5")
+
+	wait, ok := retryAfter(response)
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if wait != 5*time.Second {
+		t.Fatalf("expected 5s, got %s", wait)
+	}
+}
+
+func TestRetryAfterNegativeSeconds(t *testing.T) {
+	response := newResponseWithHeader("Retry-After", "-5")
+
+	if _, ok := retryAfter(response); ok {
+		t.Fatal("expected negative seconds to be rejected")
+	}
+}
+
+func TestRetryAfterHttpDate(t *testing.T) {
+	future := time.Now().Add(10 * time.Second).UTC()
+	response := newResponseWithHeader("Retry-After", future.Format(http.TimeFormat))
+
+	wait, ok := retryAfter(response)
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if wait <= 0 || wait > 10*time.Second {
+		t.Fatalf("expected wait within (0, 10s], got %s", wait)
+	}
+}
+
+func TestRetryAfterPastHttpDate(t *testing.T) {
+	past := time.Now().Add(-10 * time.Second).UTC()
+	response := newResponseWithHeader("Retry-After", past.Format(http.TimeFormat))
+
+	wait, ok := retryAfter(response)
+	if !ok {
+		t.Fatal("expected ok")
+	}
+	if wait != 0 {
+		t.Fatalf("expected 0 for a past date, got %s", wait)
+	}
+}
+
+func TestRetryAfterMissingOrInvalid(t *testing.T) {
+	if _, ok := retryAfter(newResponseWithHeader("Retry-After", "")); ok {
+		t.Fatal("expected ok=false when header is absent")
+	}
+	if _, ok := retryAfter(newResponseWithHeader("Retry-After", "not-a-date")); ok {
+		t.Fatal("expected ok=false for unparseable header")
+	}
+}
+
+func TestDefaultRetryPolicyShouldRetry(t *testing.T) {
+	policy := NewDefaultRetryPolicy()
+
+	request := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+
+	retryableResponse := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: make(http.Header)}
+	if shouldRetry, _ := policy.ShouldRetry(request, retryableResponse, nil, 0); !shouldRetry {
+		t.Fatal("expected 503 to be retryable")
+	}
+
+	okResponse := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+	if shouldRetry, _ := policy.ShouldRetry(request, okResponse, nil, 0); shouldRetry {
+		t.Fatal("expected 200 to not be retryable")
+	}
+
+	postRequest := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
+	if shouldRetry, _ := policy.ShouldRetry(postRequest, retryableResponse, nil, 0); shouldRetry {
+		t.Fatal("expected non-idempotent POST to not be retryable by default")
+	}
+
+	postRequest = postRequest.WithContext(withRetryNonIdempotent(postRequest.Context(), true))
+	if shouldRetry, _ := policy.ShouldRetry(postRequest, retryableResponse, nil, 0); !shouldRetry {
+		t.Fatal("expected POST to be retryable once opted in via RetryNonIdempotent")
+	}
+}
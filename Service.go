@@ -1,18 +1,22 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	errortools "github.com/leapforce-libraries/go_errortools"
+	"github.com/leapforce-libraries/go_http/internal/curl"
 	ig "github.com/leapforce-libraries/go_integration"
 	utilities "github.com/leapforce-libraries/go_utilities"
+	"google.golang.org/protobuf/proto"
 	"io"
 	"io/ioutil"
-	"math"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
@@ -27,18 +31,79 @@ const (
 	AcceptJson Accept = "json"
 	AcceptXml  Accept = "xml"
 	AcceptRaw  Accept = "raw"
+	// AcceptNDJson requests newline-delimited JSON, see Service.Stream.
+	AcceptNDJson Accept = "ndjson"
+	// AcceptEventStream requests a Server-Sent Events stream; it only sets the
+	// Accept header. Service.Stream does not parse SSE framing (the
+	// "data:"/"event:"/"id:" field prefixes and blank-line event boundaries).
+	// Use Stream for NDJSON bodies, or parse SSE framing in handler yourself.
+	AcceptEventStream Accept = "event-stream"
+	// AcceptProtobuf requests a protobuf-encoded body; ResponseModel must
+	// implement proto.Message.
+	AcceptProtobuf Accept = "protobuf"
 )
 
+// acceptHeader returns the Accept header value accept wires by default, or ""
+// for accepts that don't set one (AcceptXml and AcceptRaw keep their
+// long-standing behaviour of not setting an Accept header). It also returns
+// "" for AcceptJson, whose "application/json" header send sets itself.
+func (accept Accept) acceptHeader() string {
+	switch accept {
+	case AcceptNDJson:
+		return "application/x-ndjson"
+	case AcceptEventStream:
+		return "text/event-stream"
+	case AcceptProtobuf:
+		return "application/x-protobuf"
+	default:
+		return ""
+	}
+}
+
 type Service struct {
-	accept       Accept
-	client       http.Client
-	requestCount int64
+	accept           Accept
+	client           http.Client
+	requestCount     int64
+	defaultTimeout   *time.Duration
+	retryPolicy      RetryPolicy
+	logger           Logger
+	redactHeaders    []string
+	redactBodyFields []string
+	middlewares      []Middleware
+	dumpAsCurl       bool
 }
 
 type ServiceConfig struct {
-	Accept     *Accept
-	HttpClient *http.Client
-	ProxyUrl   *string
+	Accept         *Accept
+	HttpClient     *http.Client
+	ProxyUrl       *string
+	DefaultTimeout *time.Duration
+	// RetryPolicy, when set, replaces the library default retry policy for every
+	// request handled by this Service. Override it per request via RequestConfig.RetryPolicy.
+	RetryPolicy RetryPolicy
+	// Logger, when set, replaces the library default Logger (which renders
+	// request/response dumps to os.Stdout when ig.Debug() is true).
+	Logger Logger
+	// RedactHeaders lists header names (case-insensitive) whose values are
+	// masked as "***" before being handed to Logger.
+	RedactHeaders []string
+	// RedactBodyFields lists JSON field names (case-insensitive, at any
+	// nesting depth) whose values are masked as "***" before being handed to
+	// Logger.
+	RedactBodyFields []string
+	// TLSConfig, when set, is used as the base TLS configuration for the
+	// Service's transport.
+	TLSConfig *tls.Config
+	// RootCAs is a list of PEM-encoded certificates appended to TLSConfig's
+	// RootCAs (or a fresh pool, when TLSConfig has none) for verifying the
+	// server's certificate.
+	RootCAs [][]byte
+	// ClientCertificates is appended to TLSConfig's Certificates, enabling mTLS.
+	ClientCertificates []tls.Certificate
+	// DumpAsCurl, when true, makes every request dump itself as an equivalent
+	// curl command via Logger.LogCurl before dispatch. Override per request
+	// via RequestConfig.DumpAsCurl.
+	DumpAsCurl *bool
 }
 
 func NewService(serviceConfig *ServiceConfig) (*Service, *errortools.Error) {
@@ -53,19 +118,83 @@ func NewService(serviceConfig *ServiceConfig) (*Service, *errortools.Error) {
 			httpClient = *serviceConfig.HttpClient
 		}
 
-		if serviceConfig.ProxyUrl != nil {
-			proxyUrl, err := url.Parse(*serviceConfig.ProxyUrl)
-			if err != nil {
-				return nil, errortools.ErrorMessage(err)
+		needsTransport := serviceConfig.ProxyUrl != nil || serviceConfig.TLSConfig != nil ||
+			len(serviceConfig.RootCAs) > 0 || len(serviceConfig.ClientCertificates) > 0
+
+		if needsTransport {
+			var transport *http.Transport
+			switch existing := httpClient.Transport.(type) {
+			case nil:
+				transport = http.DefaultTransport.(*http.Transport).Clone()
+			case *http.Transport:
+				transport = existing.Clone()
+			default:
+				return nil, errortools.ErrorMessage(fmt.Errorf("HttpClient.Transport is a %T, cannot apply TLSConfig/RootCAs/ClientCertificates/ProxyUrl to it", existing))
+			}
+
+			tlsConfig := transport.TLSClientConfig
+			if serviceConfig.TLSConfig != nil {
+				tlsConfig = serviceConfig.TLSConfig.Clone()
+			} else if tlsConfig != nil {
+				tlsConfig = tlsConfig.Clone()
+			} else {
+				tlsConfig = &tls.Config{}
 			}
-			httpClient.Transport = &http.Transport{Proxy: http.ProxyURL(proxyUrl)}
+
+			if len(serviceConfig.RootCAs) > 0 {
+				certPool := tlsConfig.RootCAs
+				if certPool == nil {
+					certPool = x509.NewCertPool()
+				}
+				for _, pemBlock := range serviceConfig.RootCAs {
+					if !certPool.AppendCertsFromPEM(pemBlock) {
+						return nil, errortools.ErrorMessage(errors.New("unable to parse RootCAs PEM block"))
+					}
+				}
+				tlsConfig.RootCAs = certPool
+			}
+
+			if len(serviceConfig.ClientCertificates) > 0 {
+				tlsConfig.Certificates = append(tlsConfig.Certificates, serviceConfig.ClientCertificates...)
+			}
+
+			transport.TLSClientConfig = tlsConfig
+
+			if serviceConfig.ProxyUrl != nil {
+				proxyUrl, err := url.Parse(*serviceConfig.ProxyUrl)
+				if err != nil {
+					return nil, errortools.ErrorMessage(err)
+				}
+				transport.Proxy = http.ProxyURL(proxyUrl)
+			}
+
+			httpClient.Transport = transport
 		}
 	}
 
-	return &Service{
-		accept: accept,
-		client: httpClient,
-	}, nil
+	service := &Service{
+		accept:      accept,
+		client:      httpClient,
+		retryPolicy: NewDefaultRetryPolicy(),
+		logger:      NewDefaultLogger(),
+	}
+
+	if serviceConfig != nil {
+		service.defaultTimeout = serviceConfig.DefaultTimeout
+		if serviceConfig.RetryPolicy != nil {
+			service.retryPolicy = serviceConfig.RetryPolicy
+		}
+		if serviceConfig.Logger != nil {
+			service.logger = serviceConfig.Logger
+		}
+		service.redactHeaders = serviceConfig.RedactHeaders
+		service.redactBodyFields = serviceConfig.RedactBodyFields
+		if serviceConfig.DumpAsCurl != nil {
+			service.dumpAsCurl = *serviceConfig.DumpAsCurl
+		}
+	}
+
+	return service, nil
 }
 
 type RequestConfig struct {
@@ -80,6 +209,28 @@ type RequestConfig struct {
 	NonDefaultHeaders  *http.Header
 	XWwwFormUrlEncoded *bool
 	MaxRetries         *uint
+	// Context, when set, is used to build the outgoing request and is checked
+	// for cancellation both during the Http call and while waiting out a retry
+	// backoff. Defaults to context.Background().
+	Context context.Context
+	// Timeout, when set, overrules ServiceConfig.DefaultTimeout for this request only.
+	Timeout *time.Duration
+	// RetryPolicy, when set, overrules the Service's retry policy for this request only.
+	RetryPolicy RetryPolicy
+	// RetryNonIdempotent opts this request in to being retried even though its
+	// method (e.g. POST, PATCH) is not idempotent. Ignored by policies that
+	// don't check it.
+	RetryNonIdempotent bool
+	// DumpAsCurl, when set, overrules the Service's DumpAsCurl default for this request only.
+	DumpAsCurl *bool
+	// ResponseWriter, when set, streams the response body to it directly
+	// instead of buffering it; ResponseModel is ignored. Only applied for
+	// successful (2xx) responses.
+	ResponseWriter io.Writer
+	// ResponseDecoder, when set, overrules the Service's default decoder
+	// (json.Unmarshal, xml.Unmarshal or proto.Unmarshal, depending on Accept)
+	// for ResponseModel.
+	ResponseDecoder func(io.Reader, interface{}) error
 }
 
 func (requestConfig *RequestConfig) FullUrl() string {
@@ -98,20 +249,32 @@ func (requestConfig *RequestConfig) SetParameter(key string, value string) {
 	requestConfig.Parameters.Set(key, value)
 }
 
-func (service *Service) HttpRequest(requestConfig *RequestConfig) (*http.Request, *http.Response, *errortools.Error) {
+// send builds the outgoing request, runs it through the middleware chain and
+// the retry loop, and returns the raw response without touching its body.
+// HttpRequest and Stream both build on top of it, diverging only in how they
+// consume the response body.
+func (service *Service) send(requestConfig *RequestConfig) (*RetryableRequest, *http.Response, *errortools.Error) {
 	e := new(errortools.Error)
 
-	if ig.Debug() {
-		fmt.Printf("DEBUG - FullUrl\n%s\n", requestConfig.FullUrl())
-		fmt.Println("------------------------")
-		if !utilities.IsNil(requestConfig.ResponseModel) {
-			fmt.Printf("DEBUG - ResponseModel\n%T\n", requestConfig.ResponseModel)
-			fmt.Println("------------------------")
-		}
-		if !utilities.IsNil(requestConfig.ErrorModel) {
-			fmt.Printf("DEBUG - ErrorModel\n%T\n", requestConfig.ErrorModel)
-			fmt.Println("------------------------")
-		}
+	ctx := requestConfig.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timeout := service.defaultTimeout
+	if requestConfig.Timeout != nil {
+		timeout = requestConfig.Timeout
+	}
+	var cancel context.CancelFunc
+	if timeout != nil {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+	}
+
+	ctx = withRetryNonIdempotent(ctx, requestConfig.RetryNonIdempotent)
+
+	retryPolicy := requestConfig.RetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = service.retryPolicy
 	}
 
 	request, err := func() (*RetryableRequest, error) {
@@ -121,7 +284,7 @@ func (service *Service) HttpRequest(requestConfig *RequestConfig) (*http.Request
 		if requestConfig.BodyRaw != nil {
 			body = *requestConfig.BodyRaw
 		} else if utilities.IsNil(requestConfig.BodyModel) {
-			return NewRetryableRequest(requestConfig.Method, requestConfig.FullUrl(), nil)
+			return NewRetryableRequest(ctx, requestConfig.Method, requestConfig.FullUrl(), nil)
 		} else if service.accept == AcceptXml {
 			body, err = xml.Marshal(requestConfig.BodyModel)
 		} else {
@@ -139,23 +302,16 @@ func (service *Service) HttpRequest(requestConfig *RequestConfig) (*http.Request
 					return nil, errors.New(e.Message())
 				}
 
-				return NewRetryableRequest(requestConfig.Method, requestConfig.FullUrl(), strings.NewReader(*url))
+				return NewRetryableRequest(ctx, requestConfig.Method, requestConfig.FullUrl(), strings.NewReader(*url))
 			}
 		}
 
-		if ig.Debug() {
-			if requestConfig.BodyRaw != nil {
-				fmt.Printf("DEBUG - BodyRaw\nlength = %v, %v\n", len(*requestConfig.BodyRaw), len(body))
-				fmt.Println("------------------------")
-			} else if !utilities.IsNil(requestConfig.BodyModel) {
-				fmt.Printf("DEBUG - BodyModel\n%s\n", string(body))
-				fmt.Println("------------------------")
-			}
-		}
-
-		return NewRetryableRequest(requestConfig.Method, requestConfig.FullUrl(), bytes.NewReader(body))
+		return NewRetryableRequest(ctx, requestConfig.Method, requestConfig.FullUrl(), bytes.NewReader(body))
 	}()
 	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
 		e.SetMessage(err)
 		return nil, nil, e
 	}
@@ -169,24 +325,42 @@ func (service *Service) HttpRequest(requestConfig *RequestConfig) (*http.Request
 		if !utilities.IsNil(requestConfig.BodyModel) {
 			request.Header.Set("Content-Type", "application/json")
 		}
+	} else if accept := service.accept.acceptHeader(); accept != "" {
+		request.Header.Set("Accept", accept)
 	}
 
 	// overrule with input headers
 	if requestConfig.NonDefaultHeaders != nil {
-		if ig.Debug() {
-			fmt.Println("DEBUG - NonDefaultHeaders")
-		}
 		for key, values := range *requestConfig.NonDefaultHeaders {
 			request.Header.Del(key) //delete old header
 			for _, value := range values {
 				request.Header.Add(key, value) //add new header(s)
-				if ig.Debug() {
-					fmt.Printf("%s : %s\n", key, value)
-				}
 			}
 		}
-		if ig.Debug() {
-			fmt.Println("------------------------")
+	}
+
+	if ig.Debug() {
+		service.logger.LogRequest(RequestLog{
+			Method:  request.Method,
+			Url:     request.URL.String(),
+			Headers: redactHeaders(request.Header, service.redactHeaders),
+			Body:    redactBody(request.body, service.redactBodyFields),
+		})
+	}
+
+	dumpAsCurl := service.dumpAsCurl
+	if requestConfig.DumpAsCurl != nil {
+		dumpAsCurl = *requestConfig.DumpAsCurl
+	}
+	if dumpAsCurl {
+		command, curlErr := curl.Command(
+			request.Method,
+			request.URL.String(),
+			redactHeaders(request.Header, service.redactHeaders),
+			redactBody(request.body, service.redactBodyFields),
+		)
+		if curlErr == nil {
+			service.logger.LogCurl(command)
 		}
 	}
 
@@ -196,88 +370,202 @@ func (service *Service) HttpRequest(requestConfig *RequestConfig) (*http.Request
 
 	service.requestCount++
 
-	if ig.Debug() {
-		fmt.Printf("DEBUG - Request\n%v\n", request)
-		fmt.Println("------------------------")
-		fmt.Printf("DEBUG - Client\n%v\n", service.client)
-		fmt.Println("------------------------")
+	roundTrip := service.chain(func(r *RetryableRequest) (*http.Response, *errortools.Error) {
+		return service.doWithRetry(&service.client, r, requestConfig.MaxRetries, retryPolicy)
+	})
+
+	response, e := roundTrip(request)
+
+	if cancel != nil {
+		if response != nil && response.Body != nil {
+			response.Body = &cancelOnCloseReadCloser{ReadCloser: response.Body, cancel: cancel}
+		} else {
+			cancel()
+		}
 	}
 
-	response, e := service.doWithRetry(&service.client, request, requestConfig.MaxRetries)
+	return request, response, e
+}
 
-	if ig.Debug() {
-		fmt.Printf("DEBUG - Response\n%v\n", response)
-		fmt.Println("------------------------")
+// cancelOnCloseReadCloser defers a timeout context's cancel until the wrapped
+// body is closed, instead of canceling it the moment send returns — canceling
+// eagerly would kill the body read (and any ResponseWriter/Stream consuming
+// it) before the caller has had a chance to read it.
+type cancelOnCloseReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnCloseReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+func (service *Service) HttpRequest(requestConfig *RequestConfig) (*http.Request, *http.Response, *errortools.Error) {
+	var err error
+
+	request, response, e := service.send(requestConfig)
+	if request == nil {
+		return nil, nil, e
 	}
 
 	if response == nil {
 		return request.Request, nil, e
 	}
 
-	if response != nil {
-		if ig.Debug() {
-			fmt.Printf("DEBUG - StatusCode\n%v\n", response.StatusCode)
-			fmt.Println("------------------------")
+	if requestConfig.ResponseWriter != nil && e == nil && response.StatusCode >= 200 && response.StatusCode <= 299 {
+		defer response.Body.Close()
+
+		if _, copyErr := io.Copy(requestConfig.ResponseWriter, response.Body); copyErr != nil {
+			e = errortools.ErrorMessage(copyErr)
+			e.SetRequest(request.Request)
+			e.SetResponse(response)
+			return request.Request, response, e
 		}
 
-		if e == nil {
-			if response.StatusCode < 200 || response.StatusCode > 299 {
-				e = new(errortools.Error)
-				e.SetMessage(fmt.Sprintf("Server returned statuscode %v", response.StatusCode))
+		return request.Request, response, nil
+	}
+
+	body, errToBytes := responseBodyToBytes(response)
+
+	if ig.Debug() {
+		var logBody []byte
+		if body != nil {
+			logBody = *body
+		}
+		service.logger.LogResponse(ResponseLog{
+			Method:     request.Method,
+			Url:        request.URL.String(),
+			StatusCode: response.StatusCode,
+			Headers:    redactHeaders(response.Header, service.redactHeaders),
+			Body:       redactBody(logBody, service.redactBodyFields),
+		})
+	}
+
+	if e == nil {
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			e = new(errortools.Error)
+			e.SetMessage(fmt.Sprintf("Server returned statuscode %v", response.StatusCode))
+		}
+	}
+
+	if e != nil {
+		e.SetRequest(request.Request)
+		e.SetBody(request.body)
+		e.SetResponse(response)
+
+		if !utilities.IsNil(requestConfig.ErrorModel) && errToBytes == nil {
+			// try to unmarshal to ErrorModel
+			if err2 := service.decodeBody(*body, &requestConfig.ErrorModel); err2 != nil {
+				e.SetExtra("response_message", string(*body))
 			}
 		}
 
-		if e != nil {
+		return request.Request, response, e
+	}
+
+	if !utilities.IsNil(requestConfig.ResponseModel) {
+		// try to unmarshal to ResponseModel
+		if errToBytes != nil {
+			return request.Request, response, errToBytes
+		}
+
+		if requestConfig.ResponseDecoder != nil {
+			err = requestConfig.ResponseDecoder(bytes.NewReader(*body), requestConfig.ResponseModel)
+		} else {
+			err = service.decodeBody(*body, &requestConfig.ResponseModel)
+		}
+		if err != nil {
+			if e == nil {
+				e = new(errortools.Error)
+			}
 			e.SetRequest(request.Request)
 			e.SetBody(request.body)
 			e.SetResponse(response)
-
-			if !utilities.IsNil(requestConfig.ErrorModel) {
-				// try to unmarshal to ErrorModel
-				b, errToBytes := responseBodyToBytes(response)
-				if errToBytes == nil {
-					var err2 error
-					if service.accept == AcceptXml {
-						err2 = xml.Unmarshal(*b, &requestConfig.ErrorModel)
-					} else {
-						err2 = json.Unmarshal(*b, &requestConfig.ErrorModel)
-					}
-					if err2 != nil {
-						e.SetExtra("response_message", string(*b))
-					}
-				}
-			}
+			e.SetMessage(err)
 
 			return request.Request, response, e
 		}
+	}
 
-		if !utilities.IsNil(requestConfig.ResponseModel) {
-			// try to unmarshal to ResponseModel
-			b, errToBytes := responseBodyToBytes(response)
-			if errToBytes != nil {
-				return request.Request, response, errToBytes
-			}
+	return request.Request, response, nil
+}
 
-			if service.accept == AcceptXml {
-				err = xml.Unmarshal(*b, &requestConfig.ResponseModel)
-			} else {
-				err = json.Unmarshal(*b, &requestConfig.ResponseModel)
-			}
-			if err != nil {
-				if e == nil {
-					e = new(errortools.Error)
-				}
-				e.SetRequest(request.Request)
-				e.SetBody(request.body)
-				e.SetResponse(response)
-				e.SetMessage(err)
+// decodeBody unmarshals body into modelPtr (a pointer to the interface{}
+// field holding the caller's model) according to the Service's Accept.
+func (service *Service) decodeBody(body []byte, modelPtr *interface{}) error {
+	switch service.accept {
+	case AcceptXml:
+		return xml.Unmarshal(body, modelPtr)
+	case AcceptProtobuf:
+		message, ok := (*modelPtr).(proto.Message)
+		if !ok {
+			return fmt.Errorf("ResponseModel does not implement proto.Message")
+		}
+		return proto.Unmarshal(body, message)
+	default:
+		return json.Unmarshal(body, modelPtr)
+	}
+}
 
-				return request.Request, response, e
-			}
+// Stream executes requestConfig and invokes handler for every newline-delimited
+// record in the response body (e.g. NDJSON), without buffering the whole body
+// in memory. It does not parse Server-Sent Events framing: each line is
+// passed to handler as-is, with any "data:"/"event:"/"id:" prefix intact and
+// multi-line events not reassembled. requestConfig.ResponseModel and
+// ResponseWriter are not used by Stream.
+func (service *Service) Stream(requestConfig *RequestConfig, handler func([]byte) error) *errortools.Error {
+	request, response, e := service.send(requestConfig)
+	if request == nil {
+		return e
+	}
+	if response == nil {
+		return e
+	}
+	defer response.Body.Close()
+
+	if e != nil {
+		e.SetRequest(request.Request)
+		e.SetResponse(response)
+		return e
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		e = new(errortools.Error)
+		e.SetRequest(request.Request)
+		e.SetResponse(response)
+		e.SetMessage(fmt.Sprintf("Server returned statuscode %v", response.StatusCode))
+		return e
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		record := make([]byte, len(line))
+		copy(record, line)
+
+		if err := handler(record); err != nil {
+			e = errortools.ErrorMessage(err)
+			e.SetRequest(request.Request)
+			e.SetResponse(response)
+			return e
 		}
 	}
 
-	return request.Request, response, nil
+	if err := scanner.Err(); err != nil {
+		e = errortools.ErrorMessage(err)
+		e.SetRequest(request.Request)
+		e.SetResponse(response)
+		return e
+	}
+
+	return nil
 }
 
 func responseBodyToBytes(response *http.Response) (*[]byte, *errortools.Error) {
@@ -286,8 +574,6 @@ func responseBodyToBytes(response *http.Response) (*[]byte, *errortools.Error) {
 	}
 
 	if response.Body == nil {
-		fmt.Println("DEBUG - ResponseBody is nil")
-		fmt.Println("------------------------")
 		return nil, nil
 	}
 	defer response.Body.Close()
@@ -297,11 +583,6 @@ func responseBodyToBytes(response *http.Response) (*[]byte, *errortools.Error) {
 		return nil, errortools.ErrorMessage(err)
 	}
 
-	if ig.Debug() {
-		fmt.Printf("DEBUG - ResponseBody\n%s\n", string(b))
-		fmt.Println("------------------------")
-	}
-
 	return &b, nil
 }
 
@@ -319,7 +600,7 @@ type RetryableRequest struct {
 	*http.Request
 }
 
-func NewRetryableRequest(method, url string, reader io.Reader) (*RetryableRequest, error) {
+func NewRetryableRequest(ctx context.Context, method, url string, reader io.Reader) (*RetryableRequest, error) {
 	var body []byte = nil
 
 	if reader != nil {
@@ -331,7 +612,11 @@ func NewRetryableRequest(method, url string, reader io.Reader) (*RetryableReques
 		body = b
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -339,6 +624,11 @@ func NewRetryableRequest(method, url string, reader io.Reader) (*RetryableReques
 	return &RetryableRequest{body, 0, req}, nil
 }
 
+// AttemptCount returns how many times Do has executed the request so far.
+func (r *RetryableRequest) AttemptCount() int {
+	return r.runCount
+}
+
 func (r *RetryableRequest) Do(client *http.Client) (*http.Response, error) {
 	if r.runCount > 0 && r.body != nil {
 		reader := bytes.NewReader(r.body)
@@ -350,72 +640,68 @@ func (r *RetryableRequest) Do(client *http.Client) (*http.Response, error) {
 	return client.Do(r.Request)
 }
 
-// doWithRetry executes http.Request and retries in case of 500 range status code
-// see: https://developers.google.com/analytics/devguides/config/mgmt/v3/errors#handling_500_or_503_responses
-func (service *Service) doWithRetry(client *http.Client, request *RetryableRequest, maxRetries *uint) (*http.Response, *errortools.Error) {
+// doWithRetry executes the http.Request and consults policy after each attempt
+// to decide whether to retry and how long to back off.
+func (service *Service) doWithRetry(client *http.Client, request *RetryableRequest, maxRetries *uint, policy RetryPolicy) (*http.Response, *errortools.Error) {
 	if client == nil || request == nil {
 		if ig.Debug() {
 			if client == nil {
-				fmt.Println("DEBUG - client is nil")
-				fmt.Println("------------------------")
+				service.logger.LogDebug("client is nil")
 			}
 			if request == nil {
-				fmt.Println("DEBUG - request is nil")
-				fmt.Println("------------------------")
+				service.logger.LogDebug("request is nil")
 			}
 		}
 		return nil, nil
 	}
 
-	retry := uint(0)
+	if policy == nil {
+		policy = NewDefaultRetryPolicy()
+	}
+
 	_maxRetries := defaultMaxRetries
 	if maxRetries != nil {
 		_maxRetries = *maxRetries
 	}
 
-	statusCode := 0
-
-	for retry <= _maxRetries {
-		if retry > 0 {
-			fmt.Printf("StatusCode: %v, starting retry %v for %s %s\n", statusCode, retry, request.Method, request.URL.String())
-			waitSeconds := math.Pow(2, float64(retry-1))
-			waitMilliseconds := int(rand.Float64() * 1000)
-			time.Sleep(time.Duration(waitSeconds)*time.Second + time.Duration(waitMilliseconds)*time.Millisecond)
-		}
+	attempt := uint(0)
 
+	for {
 		response, err := request.Do(client)
-		//response, err := client.Do(request.Request)
 		if ig.Debug() {
 			if err != nil {
-				fmt.Printf("DEBUG - client.Do - error\n%s\n", err.Error())
-				fmt.Println("------------------------")
+				service.logger.LogDebug(fmt.Sprintf("client.Do - error\n%s", err.Error()))
 			}
 			if response == nil {
-				fmt.Println("DEBUG - client.Do - response is nil")
-				fmt.Println("------------------------")
+				service.logger.LogDebug("client.Do - response is nil")
 			}
 		}
 
-		if response != nil {
-			statusCode = response.StatusCode
-		} else {
-			statusCode = 0
-		}
+		if attempt < _maxRetries {
+			if shouldRetry, wait := policy.ShouldRetry(request.Request, response, err, attempt); shouldRetry {
+				if ig.Debug() {
+					service.logger.LogRetry(attempt+1, request.Method, request.URL.String(), wait)
+				}
 
-		if ig.HttpRetry(statusCode) && retry < _maxRetries {
-			retry++
-			continue
-		}
+				timer := time.NewTimer(wait)
+				select {
+				case <-request.Context().Done():
+					timer.Stop()
+					e := new(errortools.Error)
+					e.SetRequest(request.Request)
+					e.SetBody(request.body)
+					e.SetMessage(request.Context().Err())
+					return nil, e
+				case <-timer.C:
+				}
 
-		if err != nil {
-			if strings.Contains(strings.ToLower(err.Error()), "tls handshake timeout") {
-				retry++
+				attempt++
 				continue
 			}
 		}
 
-		if err == nil && (statusCode/100 == 4 || statusCode/100 == 5) {
-			err = fmt.Errorf("server returned statuscode %v", statusCode)
+		if err == nil && response != nil && (response.StatusCode/100 == 4 || response.StatusCode/100 == 5) {
+			err = fmt.Errorf("server returned statuscode %v", response.StatusCode)
 		}
 
 		if err != nil {
@@ -432,7 +718,4 @@ func (service *Service) doWithRetry(client *http.Client, request *RetryableReque
 
 		return response, nil
 	}
-
-	// should never reach this
-	return nil, nil
 }
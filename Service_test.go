@@ -0,0 +1,56 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSendDoesNotCancelBodyReadAfterReturning guards against a regression
+// where send's timeout-context cancel fired the instant send returned
+// (right after response headers arrived), killing any body read that
+// happens afterwards - e.g. the ResponseWriter/Stream streaming path from
+// chunk0-7 - with "context canceled" partway through a multi-chunk body.
+func TestSendDoesNotCancelBodyReadAfterReturning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("ResponseWriter does not support flushing")
+		}
+
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("chunk"))
+			flusher.Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	service, e := NewService(nil)
+	if e != nil {
+		t.Fatalf("NewService: %s", e.Message())
+	}
+
+	timeout := 5 * time.Second
+	var body bytes.Buffer
+
+	requestConfig := &RequestConfig{
+		Method:         http.MethodGet,
+		Url:            server.URL,
+		Timeout:        &timeout,
+		ResponseWriter: &body,
+	}
+
+	_, response, e := service.HttpRequest(requestConfig)
+	if e != nil {
+		t.Fatalf("HttpRequest: %s", e.Message())
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", response.StatusCode)
+	}
+	if body.String() != "chunkchunkchunk" {
+		t.Fatalf("expected full streamed body, got %q", body.String())
+	}
+}
@@ -0,0 +1,85 @@
+// Package curl renders outgoing Http requests as an equivalent curl command,
+// for pasting into a terminal while debugging third-party API integrations.
+package curl
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ShellEscape wraps s in single quotes, escaping any embedded single quote by
+// closing the quote, emitting an escaped quote, and reopening the quote, so
+// the result is safe to paste into a POSIX shell.
+func ShellEscape(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isBinary reports whether body should be treated as binary for curl dumping
+// purposes. A known text Content-Type (text/*, JSON, XML, form-urlencoded) is
+// never binary; for any other or missing Content-Type, body is binary when it
+// doesn't sniff as valid UTF-8.
+func isBinary(contentType string, body []byte) bool {
+	if contentType != "" {
+		mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(mediaType, "text/"):
+			return false
+		case mediaType == "application/json",
+			mediaType == "application/xml",
+			mediaType == "application/x-www-form-urlencoded":
+			return false
+		}
+	}
+
+	return !utf8.Valid(body)
+}
+
+// Command builds a curl command reproducing method, url, headers and body.
+// Binary bodies (per isBinary) are written to a temp file and referenced via
+// --data-binary @<path> instead of being inlined.
+func Command(method string, url string, headers http.Header, body []byte) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("curl -X ")
+	b.WriteString(ShellEscape(method))
+	b.WriteString(" ")
+	b.WriteString(ShellEscape(url))
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			b.WriteString(" -H ")
+			b.WriteString(ShellEscape(key + ": " + value))
+		}
+	}
+
+	if len(body) > 0 {
+		if isBinary(headers.Get("Content-Type"), body) {
+			file, err := os.CreateTemp("", "curl-body-*")
+			if err != nil {
+				return "", err
+			}
+			defer file.Close()
+
+			if _, err := file.Write(body); err != nil {
+				return "", err
+			}
+
+			b.WriteString(" --data-binary @")
+			b.WriteString(ShellEscape(file.Name()))
+		} else {
+			b.WriteString(" --data ")
+			b.WriteString(ShellEscape(string(body)))
+		}
+	}
+
+	return b.String(), nil
+}
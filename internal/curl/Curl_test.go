@@ -0,0 +1,112 @@
+package curl
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestShellEscape(t *testing.T) {
+	cases := map[string]string{
+		"hello":        "'hello'",
+		"":             "''",
+		"it's":         `'it'\''s'`,
+		"a'b'c":        `'a'\''b'\''c'`,
+		"no quote ran": "'no quote ran'",
+	}
+
+	for input, expected := range cases {
+		if got := ShellEscape(input); got != expected {
+			t.Errorf("ShellEscape(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if isBinary("application/json", []byte(`{"a":1}`)) {
+		t.Error("application/json should not be treated as binary")
+	}
+	if isBinary("text/plain; charset=utf-8", []byte("hello")) {
+		t.Error("text/plain should not be treated as binary")
+	}
+	if isBinary("application/x-www-form-urlencoded", []byte("a=b")) {
+		t.Error("form-urlencoded should not be treated as binary")
+	}
+	if !isBinary("application/octet-stream", []byte{0xff, 0xfe, 0x00, 0x01}) {
+		t.Error("octet-stream with invalid UTF-8 should be treated as binary")
+	}
+	if isBinary("", []byte("plain text body")) {
+		t.Error("valid UTF-8 with no Content-Type should not be treated as binary")
+	}
+	if !isBinary("", []byte{0xff, 0xfe, 0xfd}) {
+		t.Error("invalid UTF-8 with no Content-Type should be treated as binary")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Authorization", "Bearer abc")
+
+	command, err := Command(http.MethodPost, "https://example.com/foo", headers, []byte(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !strings.HasPrefix(command, "curl -X 'POST' 'https://example.com/foo'") {
+		t.Errorf("unexpected command prefix: %s", command)
+	}
+	if !strings.Contains(command, "-H 'Authorization: Bearer abc'") {
+		t.Errorf("expected Authorization header in command: %s", command)
+	}
+	if !strings.Contains(command, `--data '{"a":1}'`) {
+		t.Errorf("expected inline body in command: %s", command)
+	}
+}
+
+func TestCommandBinaryBody(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/octet-stream")
+
+	body := []byte{0xff, 0xfe, 0xfd, 0x00, 0x01}
+
+	command, err := Command(http.MethodPost, "https://example.com/upload", headers, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if strings.Contains(command, "--data '") {
+		t.Errorf("expected binary body to not be inlined: %s", command)
+	}
+
+	const marker = "--data-binary @"
+	idx := strings.Index(command, marker)
+	if idx == -1 {
+		t.Fatalf("expected %q in command: %s", marker, command)
+	}
+
+	escapedPath := command[idx+len(marker):]
+	if !strings.HasPrefix(escapedPath, "'") || !strings.HasSuffix(escapedPath, "'") {
+		t.Fatalf("expected shell-escaped temp file path, got %q", escapedPath)
+	}
+	path := strings.ReplaceAll(escapedPath[1:len(escapedPath)-1], `'\''`, "'")
+
+	contents, readErr := os.ReadFile(path)
+	if readErr != nil {
+		t.Fatalf("reading temp file %q: %s", path, readErr)
+	}
+	if string(contents) != string(body) {
+		t.Fatalf("temp file contents = %v, want %v", contents, body)
+	}
+}
+
+func TestCommandNoBody(t *testing.T) {
+	command, err := Command(http.MethodGet, "https://example.com", http.Header{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(command, "--data") {
+		t.Errorf("expected no --data for empty body: %s", command)
+	}
+}
@@ -0,0 +1,29 @@
+package bearerauth
+
+import (
+	errortools "github.com/leapforce-libraries/go_errortools"
+	lfhttp "github.com/leapforce-libraries/go_http"
+	"net/http"
+)
+
+// TokenSource returns the bearer token to use on the next request. It is
+// called once per RoundTrip, so implementations that refresh an expiring
+// token should cache it themselves and only refresh when needed.
+type TokenSource func() (string, *errortools.Error)
+
+// New returns a Middleware that sets the Authorization header to
+// "Bearer <token>" using the token returned by tokenSource.
+func New(tokenSource TokenSource) lfhttp.Middleware {
+	return func(next lfhttp.RoundTrip) lfhttp.RoundTrip {
+		return func(request *lfhttp.RetryableRequest) (*http.Response, *errortools.Error) {
+			token, e := tokenSource()
+			if e != nil {
+				return nil, e
+			}
+
+			request.Header.Set("Authorization", "Bearer "+token)
+
+			return next(request)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package oteltracing
+
+import (
+	errortools "github.com/leapforce-libraries/go_errortools"
+	lfhttp "github.com/leapforce-libraries/go_http"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// New returns a Middleware that wraps each RoundTrip in a span named
+// "http.request", tagged with http.method, http.url, http.status_code and
+// http.retry_count. tracerName identifies the instrumentation in traces,
+// e.g. the calling package's import path.
+func New(tracerName string) lfhttp.Middleware {
+	tracer := otel.Tracer(tracerName)
+
+	return func(next lfhttp.RoundTrip) lfhttp.RoundTrip {
+		return func(request *lfhttp.RetryableRequest) (*http.Response, *errortools.Error) {
+			ctx, span := tracer.Start(request.Context(), "http.request", trace.WithAttributes(
+				attribute.String("http.method", request.Method),
+				attribute.String("http.url", request.URL.String()),
+			))
+			defer span.End()
+
+			*request.Request = *request.Request.WithContext(ctx)
+
+			response, e := next(request)
+
+			if retryCount := request.AttemptCount() - 1; retryCount > 0 {
+				span.SetAttributes(attribute.Int("http.retry_count", retryCount))
+			}
+			if response != nil {
+				span.SetAttributes(attribute.Int("http.status_code", response.StatusCode))
+			}
+			if e != nil {
+				span.SetStatus(codes.Error, e.Message())
+			}
+
+			return response, e
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	errortools "github.com/leapforce-libraries/go_errortools"
+	lfhttp "github.com/leapforce-libraries/go_http"
+	"golang.org/x/time/rate"
+	"net/http"
+	"sync"
+)
+
+// New returns a Middleware that blocks until a token bucket limited to r
+// requests/second (with burst b) allows the request through. Each host gets
+// its own bucket, all with the same r and b. Pass rate.Inf to effectively
+// disable limiting for every host.
+func New(r rate.Limit, b int) lfhttp.Middleware {
+	var mutex sync.Mutex
+	limiters := make(map[string]*rate.Limiter)
+
+	return func(next lfhttp.RoundTrip) lfhttp.RoundTrip {
+		return func(request *lfhttp.RetryableRequest) (*http.Response, *errortools.Error) {
+			host := request.URL.Host
+
+			mutex.Lock()
+			limiter, ok := limiters[host]
+			if !ok {
+				limiter = rate.NewLimiter(r, b)
+				limiters[host] = limiter
+			}
+			mutex.Unlock()
+
+			if err := limiter.Wait(request.Context()); err != nil {
+				e := new(errortools.Error)
+				e.SetRequest(request.Request)
+				e.SetMessage(err)
+				return nil, e
+			}
+
+			return next(request)
+		}
+	}
+}